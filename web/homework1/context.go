@@ -0,0 +1,31 @@
+package web
+
+import "net/http"
+
+// HandleFunc 是注册到路由树上的业务处理函数
+type HandleFunc func(ctx *Context)
+
+// Context 是一次 HTTP 请求的上下文
+type Context struct {
+	Req  *http.Request
+	Resp http.ResponseWriter
+
+	// pathParams 是这次命中的路径参数，由 Server 从 sync.Pool 里复用的 Params 切片填入
+	pathParams Params
+	// pathParamsMap 是 PathParams 第一次被调用时惰性构建出来的 map，后续直接复用
+	pathParamsMap map[string]string
+}
+
+// PathParams 以 map[string]string 的形式返回这次命中的路径参数，兼容历史上直接用 map
+// 读取参数的用法。只有第一次调用才会真正构建这个 map。
+func (c *Context) PathParams() map[string]string {
+	if c.pathParamsMap != nil {
+		return c.pathParamsMap
+	}
+	m := make(map[string]string, len(c.pathParams))
+	for _, p := range c.pathParams {
+		m[p.Key] = p.Value
+	}
+	c.pathParamsMap = m
+	return m
+}
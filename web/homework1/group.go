@@ -0,0 +1,73 @@
+package web
+
+import "net/http"
+
+// Middleware 是一个 AOP 切面：接收下一个 HandleFunc，返回包装之后的 HandleFunc，
+// 可以在调用前后插入自己的逻辑
+type Middleware func(next HandleFunc) HandleFunc
+
+// RouteGroup 是一组有相同前缀、相同中间件链的路由。
+//
+// 中间件只能在创建分组的时候通过 mws 指定，之后没有办法再往一个已经存在的分组上追加，
+// 所以一个分组下面的路由，不管是在它有没有子分组之前注册的，应用的都是同一条中间件链——
+// 不存在“父分组后来新增的中间件要不要应用到已经注册的子路由”这个问题。
+type RouteGroup struct {
+	server *HTTPServer
+	prefix string
+	mws    []Middleware
+}
+
+// Group 基于 Server 创建一个顶层路由组
+func (s *HTTPServer) Group(prefix string, mws ...Middleware) *RouteGroup {
+	validateRoutePath(prefix)
+	return &RouteGroup{server: s, prefix: prefix, mws: mws}
+}
+
+// Group 在当前分组下面创建一个嵌套分组，前缀和中间件都是在父分组的基础上追加的
+func (g *RouteGroup) Group(prefix string, mws ...Middleware) *RouteGroup {
+	validateRoutePath(prefix)
+	chained := make([]Middleware, 0, len(g.mws)+len(mws))
+	chained = append(chained, g.mws...)
+	chained = append(chained, mws...)
+	return &RouteGroup{
+		server: g.server,
+		prefix: g.prefix + prefix,
+		mws:    chained,
+	}
+}
+
+// addRoute 把 path 拼上分组前缀，handler 套上分组的中间件链之后注册到 Server 上。
+// 中间件链在这里就被解析成了最终的 HandleFunc 存进路由树，dispatch 的时候不需要
+// 再沿着分组往上找一遍中间件
+func (g *RouteGroup) addRoute(method string, path string, handler HandleFunc) {
+	full := g.prefix + path
+	g.server.addRoute(method, full, wrapMiddlewares(handler, g.mws))
+}
+
+// GET 在这个分组下注册一个 GET 路由
+func (g *RouteGroup) GET(path string, handler HandleFunc) {
+	g.addRoute(http.MethodGet, path, handler)
+}
+
+// POST 在这个分组下注册一个 POST 路由
+func (g *RouteGroup) POST(path string, handler HandleFunc) {
+	g.addRoute(http.MethodPost, path, handler)
+}
+
+// PUT 在这个分组下注册一个 PUT 路由
+func (g *RouteGroup) PUT(path string, handler HandleFunc) {
+	g.addRoute(http.MethodPut, path, handler)
+}
+
+// DELETE 在这个分组下注册一个 DELETE 路由
+func (g *RouteGroup) DELETE(path string, handler HandleFunc) {
+	g.addRoute(http.MethodDelete, path, handler)
+}
+
+// wrapMiddlewares 把 mws 按注册顺序套在 handler 外面，mws[0] 最先开始执行
+func wrapMiddlewares(handler HandleFunc, mws []Middleware) HandleFunc {
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i](handler)
+	}
+	return handler
+}
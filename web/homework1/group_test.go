@@ -0,0 +1,61 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func orderedMiddleware(name string, trace *[]string) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			*trace = append(*trace, name+":before")
+			next(ctx)
+			*trace = append(*trace, name+":after")
+		}
+	}
+}
+
+func TestRouteGroup_MiddlewareOrderAndPrefix(t *testing.T) {
+	s := NewHTTPServer()
+	var trace []string
+	api := s.Group("/api", orderedMiddleware("api", &trace))
+	v1 := api.Group("/v1", orderedMiddleware("v1", &trace))
+	v1.GET("/ping", func(ctx *Context) {
+		trace = append(trace, "handler")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ping", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+
+	want := []string{"api:before", "v1:before", "handler", "v1:after", "api:after"}
+	if len(trace) != len(want) {
+		t.Fatalf("trace = %v, want %v", trace, want)
+	}
+	for i := range want {
+		if trace[i] != want[i] {
+			t.Fatalf("trace = %v, want %v", trace, want)
+		}
+	}
+}
+
+func TestRouteGroup_FixedMiddlewareChain(t *testing.T) {
+	s := NewHTTPServer()
+	var trace []string
+	g := s.Group("/g", orderedMiddleware("g", &trace))
+	g.GET("/before-child", func(ctx *Context) { trace = append(trace, "before-child") })
+	// 创建子分组之后，父分组自己新注册的路由走的应该是同一条（不变的）中间件链
+	_ = g.Group("/child")
+	g.GET("/after-child", func(ctx *Context) { trace = append(trace, "after-child") })
+
+	for _, p := range []string{"/g/before-child", "/g/after-child"} {
+		trace = nil
+		req := httptest.NewRequest(http.MethodGet, p, nil)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if len(trace) != 3 || trace[0] != "g:before" || trace[2] != "g:after" {
+			t.Fatalf("path %s: trace = %v", p, trace)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package web
+
+import "testing"
+
+func TestParams_ByNameAndGet(t *testing.T) {
+	ps := Params{{Key: "id", Value: "123"}, {Key: "name", Value: "tom"}}
+	if ps.ByName("id") != "123" {
+		t.Fatalf("ByName(id) = %q", ps.ByName("id"))
+	}
+	if v, ok := ps.Get("name"); !ok || v != "tom" {
+		t.Fatalf("Get(name) = %q, %v", v, ok)
+	}
+	if v, ok := ps.Get("missing"); ok || v != "" {
+		t.Fatalf("Get(missing) = %q, %v", v, ok)
+	}
+}
+
+func TestRouter_MaxParamsFor(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/user/:id", mockHandler)
+	r.addRoute("GET", "/a/:x/b/:y/c/:z", mockHandler)
+	if got := r.maxParamsFor("GET"); got != 3 {
+		t.Fatalf("maxParamsFor(GET) = %d, want 3", got)
+	}
+	if got := r.maxParamsFor("POST"); got != 0 {
+		t.Fatalf("maxParamsFor(POST) = %d, want 0", got)
+	}
+}
+
+func TestFindRoute_ParamsNoAlloc(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/user/:id", mockHandler)
+	params := make(Params, 0, r.maxParamsFor("GET"))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		info := matchInfo{params: params[:0]}
+		ok := r.findRoute("GET", "/user/123", &info)
+		if !ok || info.params.ByName("id") != "123" {
+			t.Fatalf("unexpected match result: %+v ok=%v", info, ok)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected zero allocations, got %v", allocs)
+	}
+}
+
+// TestFindRoute_ParamsNoAlloc_Backtrack 覆盖需要先走一段死路再回退的情况：
+// 死路分支（/foo/:q/bar）往 info.params 塞的参数如果没有在回退时截掉，
+// 最终命中 /:a/:b/:c 时参数个数会超过 maxParamsFor，撑爆预先分配好的切片、触发扩容分配
+func TestFindRoute_ParamsNoAlloc_Backtrack(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/foo/:q/bar", mockHandler)
+	r.addRoute("GET", "/:a/:b/:c", mockHandler)
+	params := make(Params, 0, r.maxParamsFor("GET"))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		info := matchInfo{params: params[:0]}
+		ok := r.findRoute("GET", "/foo/zzz/www", &info)
+		if !ok || len(info.params) != 3 {
+			t.Fatalf("unexpected match result: %+v ok=%v", info, ok)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected zero allocations, got %v", allocs)
+	}
+}
+
+func TestContext_PathParams(t *testing.T) {
+	ctx := &Context{pathParams: Params{{Key: "id", Value: "42"}}}
+	m := ctx.PathParams()
+	if m["id"] != "42" {
+		t.Fatalf("PathParams()[id] = %q", m["id"])
+	}
+	// 第二次调用应该复用同一个 map，而不是重新构建
+	ctx.pathParamsMap["id"] = "changed"
+	if m2 := ctx.PathParams(); m2["id"] != "changed" {
+		t.Fatalf("PathParams() 没有复用缓存的 map: %v", m2)
+	}
+}
@@ -0,0 +1,119 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouter_RedirectTrailingSlash(t *testing.T) {
+	r := newRouter()
+	r.RedirectTrailingSlash = true
+	r.addRoute("GET", "/foo/bar", mockHandler)
+
+	// 路由本身不能以 / 结尾（validateRoutePath 会 panic），所以这里只有去掉结尾 / 这一个方向有意义：
+	// 请求多打了一个结尾 /，去掉之后能找到
+	info, ok := findRoute(&r, "GET", "/foo/bar/")
+	if ok || info.redirectPath != "/foo/bar" {
+		t.Fatalf("应该修正成 /foo/bar，得到 %+v ok=%v", info, ok)
+	}
+
+	// 去掉结尾 / 之后仍然找不到，不应该给出 redirectPath
+	info, ok = findRoute(&r, "GET", "/not-registered/")
+	if ok || info.redirectPath != "" {
+		t.Fatalf("没有可以修正的路径，不应该给出 redirectPath，得到 %+v ok=%v", info, ok)
+	}
+}
+
+func TestRouter_RedirectFixedPath_CaseFold(t *testing.T) {
+	r := newRouter()
+	r.RedirectFixedPath = true
+	r.addRoute("GET", "/Foo/Bar", mockHandler)
+
+	info, ok := findRoute(&r, "GET", "/foo/bar")
+	if ok || info.redirectPath != "/Foo/Bar" {
+		t.Fatalf("应该忽略大小写修正成 /Foo/Bar，得到 %+v ok=%v", info, ok)
+	}
+
+	// 静态文件路由的大小写由文件系统说了算，fixPath 不应该尝试修正
+	r.addStaticRoute("GET", "/assets", "filepath", mockHandler)
+	if _, ok := r.redirectFor("GET", "/Assets/app.js"); ok {
+		t.Fatal("静态文件路由不应该被忽略大小写修正")
+	}
+}
+
+func TestRouter_RedirectFixedPath_DotAndDoubleSlash(t *testing.T) {
+	r := newRouter()
+	r.RedirectFixedPath = true
+	r.addRoute("GET", "/foo/bar", mockHandler)
+
+	for _, reqPath := range []string{
+		"/foo/../foo/bar",
+		"/foo//bar",
+		"/./foo/bar",
+	} {
+		info, ok := findRoute(&r, "GET", reqPath)
+		if ok || info.redirectPath != "/foo/bar" {
+			t.Fatalf("%s 应该被清理成 /foo/bar，得到 %+v ok=%v", reqPath, info, ok)
+		}
+	}
+
+	// 清理之后仍然找不到有 handler 的路径，不应该给出 redirectPath
+	info, ok := findRoute(&r, "GET", "/not-registered")
+	if ok || info.redirectPath != "" {
+		t.Fatalf("清理之后还是找不到，不应该给出 redirectPath，得到 %+v ok=%v", info, ok)
+	}
+}
+
+// TestNode_FixPathAllocsOnlyWhenFound 覆盖 fixPath 的注释里承诺的行为：
+// 只有确实找到修正结果的时候才会发生字符串拼接分配，找不到就直接返回
+func TestNode_FixPathAllocsOnlyWhenFound(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/foo/bar", mockHandler)
+	root := r.trees["GET"]
+
+	notFoundAllocs := testing.AllocsPerRun(100, func() {
+		if _, ok := root.fixPath("/totally/not/here"); ok {
+			t.Fatal("不应该找到修正结果")
+		}
+	})
+	if notFoundAllocs != 0 {
+		t.Fatalf("没找到修正结果的时候不应该有任何分配，得到 %v", notFoundAllocs)
+	}
+
+	foundAllocs := testing.AllocsPerRun(100, func() {
+		fixed, ok := root.fixPath("/FOO/BAR")
+		if !ok || fixed != "/foo/bar" {
+			t.Fatalf("应该修正成 /foo/bar，得到 %q ok=%v", fixed, ok)
+		}
+	})
+	if foundAllocs == 0 {
+		t.Fatal("找到修正结果的时候应该发生字符串拼接分配")
+	}
+}
+
+// TestServer_RedirectStatusCode 覆盖 ServeHTTP 按请求方法选择 301/307 的逻辑：
+// GET 用 301（可以被浏览器缓存），其他方法用 307（不会改变请求方法和 body，重定向之后重放）
+func TestServer_RedirectStatusCode(t *testing.T) {
+	s := NewHTTPServer()
+	s.RedirectTrailingSlash = true
+	s.GET("/foo/bar", mockHandler)
+	s.POST("/foo/bar", mockHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "/foo/bar/", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusMovedPermanently {
+		t.Fatalf("GET 重定向应该是 301，得到 %d", w.Code)
+	}
+	if loc := w.Header().Get("Location"); loc != "/foo/bar" {
+		t.Fatalf("Location 应该是 /foo/bar，得到 %q", loc)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/foo/bar/", nil)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("POST 重定向应该是 307，得到 %d", w.Code)
+	}
+}
@@ -2,7 +2,9 @@ package web
 
 import (
 	"fmt"
+	"path"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -10,14 +12,43 @@ type router struct {
 	// trees 是按照 HTTP 方法来组织的
 	// 如 GET => *node
 	trees map[string]*node
+
+	// RedirectTrailingSlash 为 true 时，findRoute 找不到精确匹配，
+	// 但是补上或者去掉结尾的 / 之后能找到一个有 handler 的节点，
+	// 会通过 matchInfo.redirectPath 给出修正后的路径，由 Server 发起重定向
+	RedirectTrailingSlash bool
+	// RedirectFixedPath 为 true 时，findRoute 找不到精确匹配，
+	// 会尝试清理路径里的 //、. 和 ..，并且忽略大小写在树里找一个规范路径，
+	// 找到的话同样通过 matchInfo.redirectPath 给出
+	RedirectFixedPath bool
+
+	// HandleMethodNotAllowed 为 true 时，findRoute 在当前方法下找不到 path，
+	// 但是别的方法下这个 path 有 handler，会通过 matchInfo.methodNotAllowed /
+	// matchInfo.allowedMethods 告诉调用方应该回 405 而不是 404
+	HandleMethodNotAllowed bool
+	// GlobalOPTIONS 是用户可以安装的钩子，当某个 path 没有显式注册 OPTIONS handler，
+	// 但是在别的方法下存在时，findRoute 会把它作为自动 OPTIONS 响应的 handler 返回，
+	// 供 Server 自定义 CORS 预检之类的响应；留空时 Server 应该自己吐一个只带 Allow 头的响应
+	GlobalOPTIONS HandleFunc
+
+	// maxParams 记录每个方法的路由树里，单条路由最多有多少个路径参数（:param / 正则），
+	// Server 用它来决定从 sync.Pool 里拿多大容量的 Params 切片，使得 findRoute 在参数
+	// 数量不超过这个值的时候不需要再扩容
+	maxParams map[string]int
 }
 
 func newRouter() router {
 	return router{
-		trees: map[string]*node{},
+		trees:     map[string]*node{},
+		maxParams: map[string]int{},
 	}
 }
 
+// maxParamsFor 返回 method 对应路由树里单条路由最多的路径参数个数
+func (r *router) maxParamsFor(method string) int {
+	return r.maxParams[method]
+}
+
 // addRoute 注册路由。
 // method 是 HTTP 方法
 // - 已经注册了的路由，无法被覆盖。例如 /user/home 注册两次，会冲突
@@ -26,9 +57,7 @@ func newRouter() router {
 // - 不能在同一个位置同时注册通配符路由和参数路由，例如 /user/:id 和 /user/* 冲突
 // - 同名路径参数，在路由匹配的时候，值会被覆盖。例如 /user/:id/abc/:id，那么 /user/123/abc/456 最终 id = 456
 func (r *router) addRoute(method string, path string, handler HandleFunc) {
-	if path == "" {
-		panic("web: 路由是空字符串")
-	}
+	validateRoutePath(path)
 	//找到对应method的路由树
 	root, ok := r.trees[method]
 	if !ok {
@@ -38,14 +67,7 @@ func (r *router) addRoute(method string, path string, handler HandleFunc) {
 		}
 		r.trees[method] = root
 	}
-	// 路径开头判断
-	if path[0] != '/' {
-		panic("web: 路由必须以 / 开头")
-	}
-	//路径结尾判断
-	if path != "/" && path[len(path)-1] == '/' {
-		panic("web: 路由不能以 / 结尾")
-	}
+	root.priority++
 	//根节点特殊处理
 	if path == "/" {
 		if root.handler != nil {
@@ -54,70 +76,280 @@ func (r *router) addRoute(method string, path string, handler HandleFunc) {
 		root.handler = handler
 		return
 	}
-	segs := strings.Split(path[1:], "/")
-	for _, seg := range segs {
-		//路径中间判断
+
+	remaining := path[1:]
+	// 路径中间判断，提前校验，保证后面压缩前缀时不用再处理空 segment
+	for _, seg := range strings.Split(remaining, "/") {
 		if seg == "" {
 			panic(fmt.Sprintf("web: 非法路由。不允许使用 //a/b, /a//b 之类的路由, [%s]", path))
 		}
-		child := root.childOrCreate(seg)
-		root = child
 	}
-	if root.handler != nil {
+
+	cur := root
+	paramCount := 0
+	for remaining != "" {
+		staticRun, dynSeg, rest := splitStaticRun(remaining)
+		if staticRun != "" {
+			cur = cur.insertStatic(staticRun)
+		}
+		if dynSeg == "" {
+			break
+		}
+		cur = cur.dynamicChildOrCreate(dynSeg)
+		cur.priority++
+		// "*" 不会产生路径参数，只有 :param 和 :name(reg) 会
+		if dynSeg != "*" {
+			paramCount++
+		}
+		remaining = rest
+	}
+	if cur.handler != nil {
 		panic("web: 路由冲突[/a/b/c]")
 	}
-	root.handler = handler
+	cur.handler = handler
+	if paramCount > r.maxParams[method] {
+		r.maxParams[method] = paramCount
+	}
 }
 
-// findRoute 查找对应的节点
-// 注意，返回的 node 内部 HandleFunc 不为 nil 才算是注册了路由
-func (r *router) findRoute(method string, path string) (*matchInfo, bool) {
+// addStaticRoute 注册一个静态文件服务节点：urlPrefix 后面剩余的整段路径会被捕获到
+// paramName 这个参数里（不是像 :param 那样一次只匹配一个 segment），交给 Server.Static
+// 包装出来的 handler。校验规则和 addRoute 一致，同一个位置已经有 :param/正则/* 路由的话
+// 会直接 panic。
+func (r *router) addStaticRoute(method string, urlPrefix string, paramName string, handler HandleFunc) {
+	validateRoutePath(urlPrefix)
 	root, ok := r.trees[method]
 	if !ok {
-		return nil, false
+		root = &node{path: "/"}
+		r.trees[method] = root
+	}
+	root.priority++
+
+	cur := root
+	paramCount := 0
+	if urlPrefix != "/" {
+		remaining := urlPrefix[1:]
+		for _, seg := range strings.Split(remaining, "/") {
+			if seg == "" {
+				panic(fmt.Sprintf("web: 非法路由。不允许使用 //a/b, /a//b 之类的路由, [%s]", urlPrefix))
+			}
+		}
+		for remaining != "" {
+			staticRun, dynSeg, rest := splitStaticRun(remaining)
+			if staticRun != "" {
+				cur = cur.insertStatic(staticRun)
+			}
+			if dynSeg == "" {
+				break
+			}
+			cur = cur.dynamicChildOrCreate(dynSeg)
+			cur.priority++
+			if dynSeg != "*" {
+				paramCount++
+			}
+			remaining = rest
+		}
+		// 补上 urlPrefix 和剩余路径之间的分隔符，让 fileChild 挂在和一条假想的
+		// "urlPrefix/:xxx" 路由完全相同的位置上，addRoute 才能正确检测出冲突
+		cur = cur.insertStatic("/")
+	}
+
+	cur = cur.fileChildOrCreate(paramName)
+	cur.priority++
+	if cur.handler != nil {
+		panic(fmt.Sprintf("web: 路由冲突，静态文件路由 [%s]", urlPrefix))
+	}
+	cur.handler = handler
+	// urlPrefix 里的 :param / 正则参数，再加上捕获剩余路径的 paramName 这一个
+	if paramCount+1 > r.maxParams[method] {
+		r.maxParams[method] = paramCount + 1
+	}
+}
+
+// findRoute 查找对应的节点，结果写进调用方传入的 info 里，而不是返回一个新分配的 matchInfo。
+// 注意，info.n 内部 HandleFunc 不为 nil 才算是注册了路由
+//
+// info 由调用方提供（通常是 Server 在处理请求时栈上创建的 matchInfo，其中 params 字段的
+// 底层数组来自 sync.Pool，容量按 maxParamsFor(method) 取），调用方需要保证传入的是一个刚
+// 初始化好、除 params（len 为 0）以外字段都是零值的 matchInfo。只要 info 的地址不逃逸到堆上并且 params 容量足够，
+// findRoute 整个过程不会发生任何分配
+//
+// 精确匹配不到的时候，如果开启了 RedirectTrailingSlash 或者 RedirectFixedPath，
+// 会尝试找一个修正后的路径，找到的话 info.redirectPath 不为空，此时返回值仍然是 false，
+// 调用方需要据此发起重定向而不是直接当成命中。
+//
+// 精确匹配和重定向都找不到的时候：
+//   - method 是 OPTIONS，并且这个 path 在别的方法下有 handler，info.autoOptions 为 true，
+//     info.n.handler 是 GlobalOPTIONS（可能为 nil）
+//   - 否则如果开启了 HandleMethodNotAllowed，并且这个 path 在别的方法下有 handler，
+//     info.methodNotAllowed 为 true，info.allowedMethods 给出允许的方法列表
+func (r *router) findRoute(method string, path string, info *matchInfo) bool {
+	root, ok := r.trees[method]
+	if ok {
+		if path == "/" {
+			info.n = root
+			return true
+		}
+		n, found := root.search(trimLeadingSlash(path), info)
+		if found {
+			info.n = n
+			return true
+		}
+		if redirectPath, ok := r.redirectFor(method, path); ok {
+			info.redirectPath = redirectPath
+			return false
+		}
+	}
+	if method == "OPTIONS" {
+		if allowed := r.allowedMethods(path); len(allowed) > 0 {
+			info.n = &node{handler: r.GlobalOPTIONS}
+			info.autoOptions = true
+			info.allowedMethods = allowed
+			return true
+		}
+	}
+	if r.HandleMethodNotAllowed {
+		if allowed := r.allowedMethods(path); len(allowed) > 0 {
+			info.methodNotAllowed = true
+			info.allowedMethods = allowed
+			return false
+		}
 	}
+	return false
+}
+
+// allowedMethods 返回 path 在哪些方法下已经注册了 handler，按字母序排列，
+// 用于自动 OPTIONS 回复和 405 的 Allow 头
+func (r *router) allowedMethods(path string) []string {
+	var allowed []string
+	for method, root := range r.trees {
+		if pathHasHandler(root, path) {
+			allowed = append(allowed, method)
+		}
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+// pathHasHandler 判断 path 在 root 代表的这棵树里精确命中并且带有 handler
+func pathHasHandler(root *node, path string) bool {
 	if path == "/" {
-		return &matchInfo{
-			n: root,
-		}, true
+		return root.handler != nil
+	}
+	info := &matchInfo{}
+	n, found := root.search(trimLeadingSlash(path), info)
+	return found && n.handler != nil
+}
+
+// redirectFor 在精确匹配失败之后，按 RedirectTrailingSlash / RedirectFixedPath 的配置
+// 尝试找一个可以命中 handler 的修正路径
+func (r *router) redirectFor(method string, reqPath string) (string, bool) {
+	root, ok := r.trees[method]
+	if !ok {
+		return "", false
 	}
-	path = strings.Trim(path, "/")
-	segs := strings.Split(path, "/")
-	var info = &matchInfo{}
-	for _, seg := range segs {
-		child, found := root.childOf(seg)
-		if !found {
-			return nil, false
+	if r.RedirectTrailingSlash {
+		var candidate string
+		if strings.HasSuffix(reqPath, "/") && reqPath != "/" {
+			candidate = strings.TrimSuffix(reqPath, "/")
+		} else {
+			candidate = reqPath + "/"
 		}
-		if child.typ == nodeTypeReg {
-			info.addValue(child.paramName, seg)
+		if r.hasHandler(method, candidate) {
+			return candidate, true
 		}
-		if child.typ == nodeTypeParam {
-			info.addValue(child.paramName, seg)
+	}
+	if r.RedirectFixedPath {
+		if fixed, ok := root.fixPath(reqPath); ok && fixed != reqPath {
+			return fixed, true
 		}
-		root = child
+	}
+	return "", false
+}
 
-		if child.typ == nodeTypeAny && child.handler != nil {
-			info.n = root
-			return info, true
+// hasHandler 判断某个 method+path 精确命中并且带有 handler
+func (r *router) hasHandler(method string, reqPath string) bool {
+	root, ok := r.trees[method]
+	if !ok {
+		return false
+	}
+	return pathHasHandler(root, reqPath)
+}
+
+// validateRoutePath 校验一段路径必须以 / 开头，不能以 / 结尾（根路径 / 除外）。
+// addRoute 和 RouteGroup 的前缀都用它来保证规则一致
+func validateRoutePath(path string) {
+	if path == "" {
+		panic("web: 路由是空字符串")
+	}
+	if path[0] != '/' {
+		panic("web: 路由必须以 / 开头")
+	}
+	if path != "/" && path[len(path)-1] == '/' {
+		panic("web: 路由不能以 / 结尾")
+	}
+}
+
+// trimLeadingSlash 去掉路径开头的 /，search 需要的是相对于根节点的剩余路径，
+// 结尾的 / 不去掉——是否匹配结尾 / 交给 RedirectTrailingSlash 处理
+func trimLeadingSlash(p string) string {
+	if p != "" && p[0] == '/' {
+		return p[1:]
+	}
+	return p
+}
+
+// splitStaticRun 从 remaining（不带开头 /）里面切出最长的一段纯静态前缀，
+// 直到遇到 :param / :name(reg) / * 这样的动态 segment 为止。
+// 返回的 dynSeg 为空代表 remaining 里已经没有动态 segment 了。
+func splitStaticRun(path string) (staticRun string, dynSeg string, rest string) {
+	pos := 0
+	for {
+		idx := strings.IndexByte(path[pos:], '/')
+		var end int
+		if idx < 0 {
+			end = len(path)
+		} else {
+			end = pos + idx
+		}
+		seg := path[pos:end]
+		if seg[0] == ':' || seg == "*" {
+			if idx < 0 {
+				return path[:pos], seg, ""
+			}
+			return path[:pos], seg, path[end+1:]
+		}
+		if idx < 0 {
+			return path, "", ""
 		}
+		pos = end + 1
+	}
+}
+
+// nextSeg 从一段不带开头 / 的路径里面切出下一个 segment
+func nextSeg(path string) (seg string, rest string) {
+	idx := strings.IndexByte(path, '/')
+	if idx < 0 {
+		return path, ""
 	}
-	// 返回的true只表明找到了对应结点，不判断 handler 是否为 nil
-	info.n = root
-	return info, true
+	return path[:idx], path[idx+1:]
 }
 
 type nodeType int
 
 const (
-	// 静态路由
-	nodeTypeStatic = iota
+	// 压缩前缀树里的静态前缀节点，原来叫 nodeTypeStatic，改名是因为这个名字现在留给
+	// 下面新增的静态文件服务节点用了
+	nodeTypeLiteral = iota
 	// 正则路由
 	nodeTypeReg
 	// 路径参数路由
 	nodeTypeParam
 	// 通配符路由
 	nodeTypeAny
+	// 静态文件服务节点：由 Server.Static 注册，把 urlPrefix 之后剩余的整段路径
+	// 捕获成一个参数，交给文件服务的 handler，而不是像 :param 那样只匹配一个 segment
+	nodeTypeStatic
 )
 
 // node 代表路由树的节点
@@ -127,13 +359,25 @@ const (
 // 3. 路径参数匹配：形式 :param_name，同一位置不允许注册多个参数
 // 4. 通配符匹配：*
 // 这是不回溯匹配
+//
+// 静态部分用压缩前缀树（radix tree）组织：children/indices 按照 httprouter 的思路，
+// 把只有一个静态子节点的链路合并成一个 node，path 上保存的是合并后的公共前缀，
+// indices 里第 i 个字节就是 children[i].path 的首字节，children 按 priority 从大到小排序，
+// 这样 findRoute 可以按字节而不是按 segment 来走树，命中率高的路由排在前面优先探测。
+// :param、正则、* 不参与前缀压缩，沿用原来每个节点挂一个单独指针的方式。
 type node struct {
 	typ nodeType
 
+	// path 是这个节点在压缩前缀树里保存的静态前缀（可能横跨多个 /）
 	path string
-	// children 子节点
-	// 子节点的 path => node
-	children map[string]*node
+	// indices 的第 i 个字节是 children[i].path 的首字节，用来做 O(1) 的子节点定位
+	indices string
+	// children 是这个节点的静态子节点，按 priority 从大到小排序
+	children []*node
+	// priority 是这个节点子树里已经注册的 handler 数量，每次 addRoute 经过都会 +1，
+	// 用来在 addRoute 之后重新排序 children，让命中多的路由排在前面
+	priority uint32
+
 	// handler 命中路由之后执行的逻辑
 	handler HandleFunc
 
@@ -141,131 +385,409 @@ type node struct {
 	starChild *node
 
 	paramChild *node
-	// 正则路由和参数路由都会使用这个字段
+	// 正则路由、参数路由和静态文件服务节点都会使用这个字段
 	paramName string
 
 	// 正则表达式
 	regChild *node
 	regExpr  *regexp.Regexp
+
+	// fileChild 是 Server.Static 挂的静态文件服务节点，和 starChild/paramChild/regChild
+	// 一样同一个位置只能存在一个，并且互相排斥——没法既是参数路由又是静态文件目录
+	fileChild *node
 }
 
-// child 返回子节点
-// 第一个返回值 *node 是命中的节点
-// 第二个返回值 bool 代表是否命中
-func (n *node) childOf(path string) (*node, bool) {
-	if n.children == nil {
-		if n.regChild != nil {
-			if n.regChild.regExpr.MatchString(path) {
-				return n.regChild, true
-			}
+// insertStatic 把一段纯静态前缀插入这个节点为根的压缩前缀树，返回代表这段前缀的节点。
+// 如果中途发现已有子节点和新前缀只有部分公共前缀，会把那个子节点拆分成两层。
+func (n *node) insertStatic(path string) *node {
+	cur := n
+	for {
+		if len(cur.children) == 0 {
+			child := cur.appendChild(path)
+			child.priority++
+			cur.sortChildren()
+			return child
 		}
-		if n.paramChild != nil {
-			return n.paramChild, true
+		idx := strings.IndexByte(cur.indices, path[0])
+		if idx < 0 {
+			child := cur.appendChild(path)
+			child.priority++
+			cur.sortChildren()
+			return child
+		}
+		child := cur.children[idx]
+		common := commonPrefixLen(path, child.path)
+		if common < len(child.path) {
+			child = cur.splitChildAt(idx, common)
 		}
-		return n.starChild, n.starChild != nil
+		child.priority++
+		cur.sortChildren()
+		if common == len(path) {
+			return child
+		}
+		path = path[common:]
+		cur = child
 	}
-	child, ok := n.children[path]
-	if !ok {
-		if n.regChild != nil {
-			if n.regChild.regExpr.MatchString(path) {
-				return n.regChild, true
-			}
+}
+
+// appendChild 给 n 新增一个静态子节点，path 是这个子节点独占的前缀
+func (n *node) appendChild(path string) *node {
+	child := &node{typ: nodeTypeLiteral, path: path}
+	n.children = append(n.children, child)
+	n.indices += string(path[0])
+	return child
+}
+
+// splitChildAt 把 n.children[idx] 在字节偏移 at 处拆开：
+// 前半段公共前缀变成新的中间节点占据原来的位置，原节点带着剩余后缀挂在它下面。
+func (n *node) splitChildAt(idx int, at int) *node {
+	old := n.children[idx]
+	parent := &node{
+		typ:      nodeTypeLiteral,
+		path:     old.path[:at],
+		priority: old.priority,
+	}
+	old.path = old.path[at:]
+	parent.children = []*node{old}
+	parent.indices = string(old.path[0])
+	n.children[idx] = parent
+	return parent
+}
+
+// sortChildren 按 priority 从大到小重新排序 children 并重建 indices，
+// 保证命中次数多的静态子节点排在前面，优先被探测到
+func (n *node) sortChildren() {
+	sort.SliceStable(n.children, func(i, j int) bool {
+		return n.children[i].priority > n.children[j].priority
+	})
+	idx := make([]byte, len(n.children))
+	for i, c := range n.children {
+		idx[i] = c.path[0]
+	}
+	n.indices = string(idx)
+}
+
+func commonPrefixLen(a, b string) int {
+	max := len(a)
+	if len(b) < max {
+		max = len(b)
+	}
+	i := 0
+	for i < max && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// matchStatic 尝试用 indices 在 n 的静态子节点里按首字节定位一个子节点，
+// 并确认 path 确实以它的 path 为前缀。只往下走一层。
+func (n *node) matchStatic(path string) (child *node, rest string, ok bool) {
+	if len(n.children) == 0 {
+		return nil, "", false
+	}
+	idx := strings.IndexByte(n.indices, path[0])
+	if idx < 0 {
+		return nil, "", false
+	}
+	c := n.children[idx]
+	if !strings.HasPrefix(path, c.path) {
+		return nil, "", false
+	}
+	return c, path[len(c.path):], true
+}
+
+// search 从 n 开始，按字节/segment 匹配 path，返回命中的节点。
+//
+// 静态前缀优先尝试：如果沿着压缩前缀树匹配到了某个静态子节点，但是从那个子节点继续往下
+// 找不到带 handler 的节点（停在没有 handler 的中间节点上也算；典型情况是这个子节点的 path 只是当前 segment 的一部分公共前缀，请求在
+// 这个 segment 剩下的部分和树里的static前缀对不上），会回退到当前节点，改用完整的这一个
+// segment 去试正则/参数/通配符/静态文件子节点——这四者在同一个节点上互斥，最多存在一种，
+// 所以这一层回退到此为止，不会有更深层次的全量回溯。
+func (n *node) search(path string, info *matchInfo) (*node, bool) {
+	if path == "" {
+		// path 刚好在分隔符节点上用完（比如静态文件挂载点本身的 "/static/"），这个节点自己
+		// 没有 handler，但是挂了 fileChild 的话，相当于捕获了一个空的剩余路径（挂载目录本身）
+		if n.handler == nil && n.fileChild != nil {
+			info.addValue(n.fileChild.paramName, "")
+			return n.fileChild, true
 		}
-		if n.paramChild != nil {
-			return n.paramChild, true
+		return n, true
+	}
+
+	if child, rest, ok := n.matchStatic(path); ok {
+		mark := len(info.params)
+		if hit, found := child.search(rest, info); found && hit.handler != nil {
+			return hit, true
 		}
-		return n.starChild, n.starChild != nil
+		// 静态前缀往下是死路，回退到下面用完整 segment 重试正则/参数/通配符/静态文件；
+		// 失败的那次探测可能已经顺着 regChild/paramChild 往更深处塞了几个参数进去，
+		// 必须截掉，否则这次回退命中之后 info.params 里会混进死路分支遗留的脏数据
+		info.params = info.params[:mark]
+	}
+
+	// fileChild 捕获的是剩下的整段路径而不是一个 segment，要在按 / 切 segment 之前判断，
+	// 否则路径里的 / 会被 nextSeg 当成 segment 边界，把 "/css/a.css" 错误地切成空 seg
+	if n.fileChild != nil {
+		info.addValue(n.fileChild.paramName, strings.TrimPrefix(path, "/"))
+		return n.fileChild, true
+	}
+
+	seg, rest := nextSeg(path)
+	switch {
+	case n.regChild != nil && n.regChild.regExpr.MatchString(seg):
+		info.addValue(n.regChild.paramName, seg)
+		return n.regChild.search(rest, info)
+	case n.paramChild != nil:
+		info.addValue(n.paramChild.paramName, seg)
+		return n.paramChild.search(rest, info)
+	case n.starChild != nil:
+		// "*" 匹配到这一个 segment 就算命中，不管后面还有没有更多 segment；只有通配符
+		// 本身没有 handler（比如 "/a/*/b" 这种通配符后面还接了别的路由）才会继续往下找
+		if n.starChild.handler != nil {
+			return n.starChild, true
+		}
+		return n.starChild.search(rest, info)
+	default:
+		return nil, false
 	}
-	return child, ok
 }
 
-// childOrCreate 查找子节点，
-// 首先会判断 path 是不是正则路径，即路径包含 (
-// 其次判断 path 是不是参数路径，即以 : 开头的路径
-// 然后判断 path 是不是通配符路径
-// 最后会从 children 里面查找，
-// 如果没有找到，那么会创建一个新的节点，并且保存在 node 里面
-func (n *node) childOrCreate(path string) *node {
-	if strings.Contains(path, "(") {
+// dynamicChildOrCreate 查找或者创建 seg 对应的动态子节点（正则 / 参数 / 通配符），
+// 这三类节点不参与静态前缀压缩，每个位置各自最多只有一个。
+// 首先会判断 seg 是不是正则路径，即路径包含 (
+// 其次判断 seg 是不是参数路径，即以 : 开头的路径
+// 然后判断 seg 是不是通配符路径
+func (n *node) dynamicChildOrCreate(seg string) *node {
+	if strings.Contains(seg, "(") {
 		if n.starChild != nil {
-			panic(fmt.Sprintf("web: 非法路由，已有通配符路由。不允许同时注册通配符路由和正则路由 [%s]", path))
+			panic(fmt.Sprintf("web: 非法路由，已有通配符路由。不允许同时注册通配符路由和正则路由 [%s]", seg))
 		}
 		if n.paramChild != nil {
-			panic(fmt.Sprintf("web: 非法路由，已有路径参数路由。不允许同时注册正则路由和参数路由 [%s]", path))
+			panic(fmt.Sprintf("web: 非法路由，已有路径参数路由。不允许同时注册正则路由和参数路由 [%s]", seg))
+		}
+		if n.fileChild != nil {
+			panic(fmt.Sprintf("web: 非法路由，已有静态文件路由。不允许同时注册静态文件路由和正则路由 [%s]", seg))
 		}
 		if n.regChild != nil {
-			if n.regChild.path != path {
-				panic(fmt.Sprintf("web: 路由冲突，正则冲突，已有 %s，新注册 %s", n.regChild.path, path))
+			if n.regChild.path != seg {
+				panic(fmt.Sprintf("web: 路由冲突，正则冲突，已有 %s，新注册 %s", n.regChild.path, seg))
 			}
 			return n.regChild
 		}
 		n.regChild = &node{
 			typ:       nodeTypeReg,
-			path:      path,
-			paramName: path[1:strings.Index(path, "(")],
-			regExpr:   regexp.MustCompile(path[strings.Index(path, "(")+1 : strings.Index(path, ")")]),
+			path:      seg,
+			paramName: seg[1:strings.Index(seg, "(")],
+			regExpr:   regexp.MustCompile(seg[strings.Index(seg, "(")+1 : strings.Index(seg, ")")]),
 		}
 		return n.regChild
 	}
-	if path[0] == ':' {
+	if seg[0] == ':' {
 		if n.starChild != nil {
-			panic(fmt.Sprintf("web: 非法路由，已有通配符路由。不允许同时注册通配符路由和参数路由 [%s]", path))
+			panic(fmt.Sprintf("web: 非法路由，已有通配符路由。不允许同时注册通配符路由和参数路由 [%s]", seg))
 		}
 		if n.regChild != nil {
-			panic(fmt.Sprintf("web: 非法路由，已有正则路由。不允许同时注册正则路由和参数路由 [%s]", path))
+			panic(fmt.Sprintf("web: 非法路由，已有正则路由。不允许同时注册正则路由和参数路由 [%s]", seg))
+		}
+		if n.fileChild != nil {
+			panic(fmt.Sprintf("web: 非法路由，已有静态文件路由。不允许同时注册静态文件路由和参数路由 [%s]", seg))
 		}
 		if n.paramChild != nil {
-			if n.paramChild.path != path {
-				panic(fmt.Sprintf("web: 路由冲突，参数路由冲突，已有 %s，新注册 %s", n.paramChild.path, path))
+			if n.paramChild.path != seg {
+				panic(fmt.Sprintf("web: 路由冲突，参数路由冲突，已有 %s，新注册 %s", n.paramChild.path, seg))
 			}
 			return n.paramChild
 		}
-		n.paramChild = &node{
-			typ:       nodeTypeParam,
-			path:      path,
-			paramName: path[1:],
-		}
+		n.paramChild = &node{typ: nodeTypeParam, path: seg, paramName: seg[1:]}
 		return n.paramChild
 	}
-	if path == "*" {
-		if n.paramChild != nil {
-			panic("web: 非法路由，已有路径参数路由。不允许同时注册通配符路由和参数路由 [*]")
+	// seg == "*"
+	if n.paramChild != nil {
+		panic("web: 非法路由，已有路径参数路由。不允许同时注册通配符路由和参数路由 [*]")
+	}
+	if n.regChild != nil {
+		panic("web: 非法路由，已有正则路由。不允许同时注册通配符路由和正则路由 [*]")
+	}
+	if n.fileChild != nil {
+		panic("web: 非法路由，已有静态文件路由。不允许同时注册通配符路由和静态文件路由 [*]")
+	}
+	if n.starChild != nil {
+		return n.starChild
+	}
+	n.starChild = &node{typ: nodeTypeAny, path: "*"}
+	return n.starChild
+}
+
+// fileChildOrCreate 查找或者创建 n 下面的静态文件服务节点，由 Server.Static 在注册的时候调用。
+// 和 :param/正则/* 一样，同一个位置只能存在一种动态匹配方式，不允许混用。
+func (n *node) fileChildOrCreate(paramName string) *node {
+	if n.starChild != nil {
+		panic("web: 非法路由，已有通配符路由。不允许同时注册通配符路由和静态文件路由")
+	}
+	if n.regChild != nil {
+		panic("web: 非法路由，已有正则路由。不允许同时注册正则路由和静态文件路由")
+	}
+	if n.paramChild != nil {
+		panic("web: 非法路由，已有路径参数路由。不允许同时注册路径参数路由和静态文件路由")
+	}
+	if n.fileChild != nil {
+		return n.fileChild
+	}
+	n.fileChild = &node{typ: nodeTypeStatic, path: "*" + paramName, paramName: paramName}
+	return n.fileChild
+}
+
+// fixPath 清理 reqPath 里的 //、. 和 ..，再忽略大小写在 n 代表的树里找一个规范路径。
+// 只有确实找到修正结果的时候才会发生字符串拼接分配，找不到就直接返回。
+func (n *node) fixPath(reqPath string) (string, bool) {
+	cleaned := path.Clean(reqPath)
+	if cleaned == "" {
+		cleaned = "/"
+	}
+	if cleaned == "/" {
+		if n.handler != nil {
+			return "/", true
 		}
-		if n.regChild != nil {
-			panic("web: 非法路由，已有正则路由。不允许同时注册通配符路由和正则路由 [*]")
+		return "", false
+	}
+	trimmed := strings.Trim(cleaned, "/")
+	if !n.matchCaseInsensitive(trimmed) {
+		return "", false
+	}
+	var b strings.Builder
+	b.WriteByte('/')
+	n.buildCaseInsensitive(trimmed, &b)
+	return b.String(), true
+}
+
+// matchCaseInsensitive 只判断忽略大小写能不能在树里找到一个有 handler 的节点，不做任何分配
+func (n *node) matchCaseInsensitive(p string) bool {
+	cur := n
+	for p != "" {
+		if child, rest, ok := cur.matchStaticFold(p); ok {
+			cur = child
+			p = rest
+			if cur.typ == nodeTypeAny && cur.handler != nil {
+				return true
+			}
+			continue
 		}
-		if n.starChild != nil {
-			return n.starChild
+		// 静态文件路由的大小写由文件系统说了算，忽略大小写去修正一个文件路径没有意义，
+		// 所以这里直接放弃，不尝试走到 fileChild 里面去
+		if cur.fileChild != nil {
+			return false
 		}
-		n.starChild = &node{
-			typ:  nodeTypeAny,
-			path: path,
+		seg, rest := nextSeg(p)
+		switch {
+		case cur.regChild != nil && cur.regChild.regExpr.MatchString(seg):
+			cur = cur.regChild
+		case cur.paramChild != nil:
+			cur = cur.paramChild
+		case cur.starChild != nil:
+			cur = cur.starChild
+		default:
+			return false
+		}
+		p = rest
+		if cur.typ == nodeTypeAny && cur.handler != nil {
+			return true
 		}
-		return n.starChild
 	}
-	if n.children == nil {
-		n.children = map[string]*node{}
+	return cur.handler != nil
+}
+
+// buildCaseInsensitive 重新走一遍 matchCaseInsensitive 已经确认存在的路径，
+// 把命中的静态前缀按树里保存的规范大小写写回 b，动态 segment 原样保留调用方传入的大小写
+func (n *node) buildCaseInsensitive(p string, b *strings.Builder) {
+	cur := n
+	for p != "" {
+		if child, rest, ok := cur.matchStaticFold(p); ok {
+			b.WriteString(child.path)
+			cur = child
+			p = rest
+			if cur.typ == nodeTypeAny && cur.handler != nil {
+				return
+			}
+			continue
+		}
+		seg, rest := nextSeg(p)
+		switch {
+		case cur.regChild != nil && cur.regChild.regExpr.MatchString(seg):
+			cur = cur.regChild
+		case cur.paramChild != nil:
+			cur = cur.paramChild
+		case cur.starChild != nil:
+			cur = cur.starChild
+		}
+		b.WriteString(seg)
+		if rest != "" {
+			b.WriteByte('/')
+		}
+		p = rest
+		if cur.typ == nodeTypeAny && cur.handler != nil {
+			return
+		}
 	}
-	res, ok := n.children[path]
-	if !ok {
-		res = &node{
-			typ:  nodeTypeStatic,
-			path: path,
+}
+
+// matchStaticFold 和 matchStatic 类似，但是忽略大小写，并且线性扫描所有静态子节点
+// （indices 是按照精确字节排的，忽略大小写没法直接用它做 O(1) 定位），
+// 只在路径修正这条冷路径上使用
+func (n *node) matchStaticFold(p string) (*node, string, bool) {
+	for _, c := range n.children {
+		if len(c.path) <= len(p) && strings.EqualFold(p[:len(c.path)], c.path) {
+			return c, p[len(c.path):], true
 		}
-		n.children[path] = res
 	}
-	return res
+	return nil, "", false
+}
+
+// Param 是一个路径参数的键值对
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params 是一次路由命中产生的所有路径参数。相比 map[string]string，它可以从 sync.Pool
+// 里复用的定长 slice 上 append，在参数个数不超过 router.maxParamsFor(method) 时做到零分配
+type Params []Param
+
+// ByName 返回 name 对应的参数值，不存在的话返回空字符串
+func (ps Params) ByName(name string) string {
+	v, _ := ps.Get(name)
+	return v
+}
+
+// Get 返回 name 对应的参数值，第二个返回值代表这个参数是否存在。
+// 同名参数出现多次时取最后一个，和 Context.PathParams 构建出来的 map 一致
+func (ps Params) Get(name string) (string, bool) {
+	for i := len(ps) - 1; i >= 0; i-- {
+		if ps[i].Key == name {
+			return ps[i].Value, true
+		}
+	}
+	return "", false
 }
 
 type matchInfo struct {
-	n          *node
-	pathParams map[string]string
+	n      *node
+	params Params
+	// redirectPath 不为空时，代表没有命中精确路由，但是按 RedirectTrailingSlash /
+	// RedirectFixedPath 修正之后的路径在树里有 handler，Server 应该对这个路径发起
+	// 301（GET）或者 307（其他方法）重定向
+	redirectPath string
+	// methodNotAllowed 为 true 时，代表 path 在别的方法下有 handler，Server 应该用
+	// allowedMethods 设置 Allow 头并且回 405，而不是 404
+	methodNotAllowed bool
+	// autoOptions 为 true 时，代表这是路由树自动合成的 OPTIONS 响应——用户没有给这个
+	// path 显式注册 OPTIONS handler，n.handler 是 router.GlobalOPTIONS（可能为 nil，
+	// 这时 Server 应该只设置 Allow 头然后回 200）
+	autoOptions bool
+	// allowedMethods 在 methodNotAllowed 或者 autoOptions 为 true 时，给出这个 path
+	// 已经注册了 handler 的方法列表，按字母序排列
+	allowedMethods []string
 }
 
 func (m *matchInfo) addValue(key string, value string) {
-	if m.pathParams == nil {
-		// 大多数情况，参数路径只会有一段
-		m.pathParams = map[string]string{key: value}
-	}
-	m.pathParams[key] = value
+	m.params = append(m.params, Param{Key: key, Value: value})
 }
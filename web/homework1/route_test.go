@@ -0,0 +1,200 @@
+package web
+
+import "testing"
+
+func mockHandler(ctx *Context) {}
+
+// findRoute 是测试专用的小包装：产品代码里 router.findRoute 把结果写进调用方传入的 info，
+// 这里补一个刚初始化好的 matchInfo，让测试代码还是能用熟悉的 (info, ok) 两个返回值
+func findRoute(r *router, method string, path string) (*matchInfo, bool) {
+	info := &matchInfo{}
+	ok := r.findRoute(method, path, info)
+	return info, ok
+}
+
+func TestRouter_MethodNotAllowed(t *testing.T) {
+	r := newRouter()
+	r.HandleMethodNotAllowed = true
+	r.addRoute("GET", "/user/:id", mockHandler)
+	r.addRoute("POST", "/user/:id", mockHandler)
+	r.addRoute("GET", "/files/*", mockHandler)
+
+	info, ok := findRoute(&r, "DELETE", "/user/123")
+	if ok {
+		t.Fatal("DELETE /user/123 不应该被当成命中")
+	}
+	if !info.methodNotAllowed {
+		t.Fatalf("期望 methodNotAllowed = true，得到 %+v", info)
+	}
+	if len(info.allowedMethods) != 2 || info.allowedMethods[0] != "GET" || info.allowedMethods[1] != "POST" {
+		t.Fatalf("allowedMethods 不符合预期: %v", info.allowedMethods)
+	}
+
+	// 通配符路由同样要能被 405 识别到
+	info, ok = findRoute(&r, "POST", "/files/a/b.txt")
+	if ok || !info.methodNotAllowed {
+		t.Fatalf("通配符路由的 405 没有生效: %+v ok=%v", info, ok)
+	}
+
+	// 完全不存在的路径，不应该返回 405
+	info, ok = findRoute(&r, "GET", "/not-registered")
+	if ok || info.methodNotAllowed || info.redirectPath != "" {
+		t.Fatalf("完全没有注册的路径不应该命中 405: %+v ok=%v", info, ok)
+	}
+}
+
+func TestRouter_AutoOptions(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/user/:id", mockHandler)
+	r.addRoute("POST", "/user/:id", mockHandler)
+
+	info, ok := findRoute(&r, "OPTIONS", "/user/123")
+	if !ok || !info.autoOptions {
+		t.Fatalf("期望自动合成 OPTIONS 响应，得到 %+v ok=%v", info, ok)
+	}
+	if len(info.allowedMethods) != 2 {
+		t.Fatalf("allowedMethods 不符合预期: %v", info.allowedMethods)
+	}
+
+	// 用户显式注册了 OPTIONS，应该走正常命中逻辑而不是自动合成
+	r.addRoute("OPTIONS", "/user/:id", mockHandler)
+	info, ok = findRoute(&r, "OPTIONS", "/user/123")
+	if !ok || info.autoOptions {
+		t.Fatalf("期望命中显式注册的 OPTIONS handler，得到 %+v ok=%v", info, ok)
+	}
+
+	// 没有任何方法注册过这个路径，OPTIONS 也不应该自动合成
+	info, ok = findRoute(&r, "OPTIONS", "/not-registered")
+	if ok || info.autoOptions || info.redirectPath != "" {
+		t.Fatalf("未注册路径不应该自动合成 OPTIONS: %+v ok=%v", info, ok)
+	}
+}
+
+// TestRouter_StaticParamDivergence 覆盖静态前缀和 :param 在同一个 segment 上分叉的情况：
+// 压缩前缀树沿着 "new" 往下走到死路之后，必须回退到 /user/:id 重新匹配，而不是直接 404
+func TestRouter_StaticParamDivergence(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/user/new", mockHandler)
+	r.addRoute("GET", "/user/:id", mockHandler)
+
+	info, ok := findRoute(&r, "GET", "/user/new")
+	if !ok || info.n.handler == nil || len(info.params) != 0 {
+		t.Fatalf("/user/new 应该精确命中静态路由且不带参数，得到 %+v ok=%v", info, ok)
+	}
+
+	for _, tc := range []struct {
+		path string
+		id   string
+	}{
+		{"/user/newX", "newX"},
+		{"/user/newbie", "newbie"},
+	} {
+		info, ok := findRoute(&r, "GET", tc.path)
+		if !ok || info.params.ByName("id") != tc.id {
+			t.Fatalf("%s 应该回退匹配到 :id = %q，得到 %+v ok=%v", tc.path, tc.id, info, ok)
+		}
+	}
+}
+
+// TestRouter_StaticParamDivergence_ParamLeak 覆盖死路分支自己也带 :param 的情况：
+// /foo/:id/bar 先被尝试，往下走到 bar 这一段失败，回退到 /:id/x/baz 的时候，
+// 死路分支顺路塞进 info.params 的 "id" 不应该残留下来
+func TestRouter_StaticParamDivergence_ParamLeak(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/foo/:id/bar", mockHandler)
+	r.addRoute("GET", "/:id/x/baz", mockHandler)
+
+	info, ok := findRoute(&r, "GET", "/foo/x/baz")
+	if !ok {
+		t.Fatalf("/foo/x/baz 应该回退命中 /:id/x/baz，得到 ok=%v", ok)
+	}
+	if len(info.params) != 1 || info.params.ByName("id") != "foo" {
+		t.Fatalf("死路分支遗留的参数不应该残留，期望只有 id=foo，得到 %+v", info.params)
+	}
+
+	// 死路分支自己也可能往深处塞好几个参数：/foo/:q/bar 会先把 q 塞进去，
+	// 到 bar 失败之后回退到 /:a/:b/:c，不应该把 q 也算进最终的参数列表
+	r2 := newRouter()
+	r2.addRoute("GET", "/foo/:q/bar", mockHandler)
+	r2.addRoute("GET", "/:a/:b/:c", mockHandler)
+
+	info, ok = findRoute(&r2, "GET", "/foo/zzz/www")
+	if !ok {
+		t.Fatalf("/foo/zzz/www 应该回退命中 /:a/:b/:c，得到 ok=%v", ok)
+	}
+	if len(info.params) != 3 {
+		t.Fatalf("期望只有 a/b/c 三个参数，得到 %+v", info.params)
+	}
+}
+
+// TestRouter_StaticWildcardDivergence 覆盖静态前缀和 * 在同一个 segment 上分叉的情况
+func TestRouter_StaticWildcardDivergence(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/files/index", mockHandler)
+	r.addRoute("GET", "/files/*", mockHandler)
+
+	info, ok := findRoute(&r, "GET", "/files/index")
+	if !ok || len(info.params) != 0 {
+		t.Fatalf("/files/index 应该精确命中静态路由，得到 %+v ok=%v", info, ok)
+	}
+
+	info, ok = findRoute(&r, "GET", "/files/indexX/y")
+	if !ok || info.n.handler == nil {
+		t.Fatalf("静态前缀死路之后应该回退匹配到通配符，得到 %+v ok=%v", info, ok)
+	}
+}
+
+// TestNode_StaticSplit 覆盖压缩前缀树在公共前缀上拆分节点的情况：
+// /foo/bar 和 /foo/baz 只有 "foo/ba" 是公共前缀，注册第二条路由时应该把原来的
+// "foo/bar" 节点拆成 "foo/ba" -> "r"/"z" 两层
+func TestNode_StaticSplit(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/foo/bar", mockHandler)
+	r.addRoute("GET", "/foo/baz", mockHandler)
+
+	root := r.trees["GET"]
+	if len(root.children) != 1 || root.children[0].path != "foo/ba" {
+		t.Fatalf("公共前缀应该被拆分成一个 foo/ba 节点，得到 %+v", root.children)
+	}
+	mid := root.children[0]
+	if len(mid.children) != 2 {
+		t.Fatalf("foo/ba 下面应该有 r 和 z 两个子节点，得到 %+v", mid.children)
+	}
+
+	for _, path := range []string{"/foo/bar", "/foo/baz"} {
+		info, ok := findRoute(&r, "GET", path)
+		if !ok || info.n.handler == nil {
+			t.Fatalf("%s 应该命中，得到 %+v ok=%v", path, info, ok)
+		}
+	}
+	if info, ok := findRoute(&r, "GET", "/foo/ba"); ok && info.n.handler != nil {
+		t.Fatal("/foo/ba 本身没有注册 handler，不应该算命中")
+	}
+}
+
+// TestNode_PriorityReordering 覆盖 children 按 priority 重新排序的情况：
+// aaa 下面后续又挂了一条子路由，priority 应该超过 zzz 并且排到前面
+func TestNode_PriorityReordering(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/zzz", mockHandler)
+	r.addRoute("GET", "/aaa", mockHandler)
+
+	root := r.trees["GET"]
+	if root.indices != "za" {
+		t.Fatalf("priority 相同时应该保持注册顺序 za，得到 %q", root.indices)
+	}
+
+	r.addRoute("GET", "/aaa/bbb", mockHandler)
+	if root.indices != "az" {
+		t.Fatalf("aaa 的 priority 变高之后应该排到 zzz 前面，得到 %q", root.indices)
+	}
+	if root.children[0].path != "aaa" || root.children[0].priority != 2 {
+		t.Fatalf("aaa 节点的 priority 应该是 2，得到 %+v", root.children[0])
+	}
+
+	for _, path := range []string{"/zzz", "/aaa", "/aaa/bbb"} {
+		if info, ok := findRoute(&r, "GET", path); !ok || info.n.handler == nil {
+			t.Fatalf("%s 应该命中，得到 %+v ok=%v", path, info, ok)
+		}
+	}
+}
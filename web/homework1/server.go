@@ -0,0 +1,113 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// HTTPServer 是基于 router 的默认 HTTP 服务端实现
+type HTTPServer struct {
+	router
+
+	// paramsPools 按 method 维护一个 sync.Pool，每个 pool 里的 Params 切片容量
+	// 都是 maxParamsFor(method)，这样 findRoute 在命中一条普通路由时不需要再分配
+	paramsPools   map[string]*sync.Pool
+	paramsPoolsMu sync.Mutex
+}
+
+// NewHTTPServer 创建一个空的 HTTPServer，还没有注册任何路由
+func NewHTTPServer() *HTTPServer {
+	return &HTTPServer{
+		router:      newRouter(),
+		paramsPools: map[string]*sync.Pool{},
+	}
+}
+
+// GET 注册一个顶层 GET 路由，不经过任何路由组、不带任何中间件
+func (s *HTTPServer) GET(path string, handler HandleFunc) {
+	s.addRoute(http.MethodGet, path, handler)
+}
+
+// POST 注册一个顶层 POST 路由，不经过任何路由组、不带任何中间件
+func (s *HTTPServer) POST(path string, handler HandleFunc) {
+	s.addRoute(http.MethodPost, path, handler)
+}
+
+// PUT 注册一个顶层 PUT 路由，不经过任何路由组、不带任何中间件
+func (s *HTTPServer) PUT(path string, handler HandleFunc) {
+	s.addRoute(http.MethodPut, path, handler)
+}
+
+// DELETE 注册一个顶层 DELETE 路由，不经过任何路由组、不带任何中间件
+func (s *HTTPServer) DELETE(path string, handler HandleFunc) {
+	s.addRoute(http.MethodDelete, path, handler)
+}
+
+func (s *HTTPServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	params := s.getParams(req.Method)
+	defer s.putParams(req.Method, params)
+
+	info := matchInfo{params: params}
+	ok := s.findRoute(req.Method, req.URL.Path, &info)
+
+	if !ok {
+		switch {
+		case info.redirectPath != "":
+			code := http.StatusMovedPermanently
+			if req.Method != http.MethodGet {
+				code = http.StatusTemporaryRedirect
+			}
+			http.Redirect(w, req, info.redirectPath, code)
+		case info.methodNotAllowed:
+			w.Header().Set("Allow", strings.Join(info.allowedMethods, ", "))
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+		return
+	}
+
+	if info.autoOptions {
+		w.Header().Set("Allow", strings.Join(info.allowedMethods, ", "))
+		if info.n.handler == nil {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+
+	if info.n.handler == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	ctx := &Context{Req: req, Resp: w, pathParams: info.params}
+	info.n.handler(ctx)
+}
+
+// getParams 从 req.Method 对应的 sync.Pool 里取一个容量足够的 Params 切片，len 总是 0
+func (s *HTTPServer) getParams(method string) Params {
+	pool := s.poolFor(method)
+	return pool.Get().(Params)[:0]
+}
+
+// putParams 把用完的 Params 切片放回对应的 sync.Pool
+func (s *HTTPServer) putParams(method string, params Params) {
+	s.poolFor(method).Put(params)
+}
+
+func (s *HTTPServer) poolFor(method string) *sync.Pool {
+	s.paramsPoolsMu.Lock()
+	defer s.paramsPoolsMu.Unlock()
+	pool, ok := s.paramsPools[method]
+	if !ok {
+		capacity := s.maxParamsFor(method)
+		pool = &sync.Pool{
+			New: func() any {
+				return make(Params, 0, capacity)
+			},
+		}
+		s.paramsPools[method] = pool
+	}
+	return pool
+}
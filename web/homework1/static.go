@@ -0,0 +1,182 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// staticFilePathParam 是 Static 注册的节点捕获剩余路径时用的参数名
+const staticFilePathParam = "filepath"
+
+// StaticOption 用来定制 Server.Static 注册的静态文件路由
+type StaticOption func(*staticServer)
+
+// WithFS 指定静态文件的来源，默认是 os.DirFS(fsRoot)。
+// 传入 embed.FS 之类的 fs.FS 可以把资源直接打进二进制，这时 fsRoot 不再被使用。
+func WithFS(fsys fs.FS) StaticOption {
+	return func(s *staticServer) {
+		s.fsys = fsys
+	}
+}
+
+// WithDirListing 打开目录列表功能，访问到一个目录的时候返回文件列表而不是 404（默认关闭）
+func WithDirListing() StaticOption {
+	return func(s *staticServer) {
+		s.listDir = true
+	}
+}
+
+// WithStaticNotFound 自定义文件不存在（或者目录列表关闭时访问到目录）的响应，
+// 默认只是写一个 404 状态码
+func WithStaticNotFound(handler HandleFunc) StaticOption {
+	return func(s *staticServer) {
+		s.notFound = handler
+	}
+}
+
+// staticServer 保存了一次 Server.Static 调用的配置，serveHandler 拿着它去处理每一次请求
+type staticServer struct {
+	fsys     fs.FS
+	listDir  bool
+	notFound HandleFunc
+}
+
+// Static 把 urlPrefix 下面的请求映射到 fsRoot 目录（或者通过 WithFS 指定的 fs.FS）下的文件。
+// 内部注册成一个 nodeTypeStatic 节点，urlPrefix 之后剩余的路径会被整体捕获到 filepath 参数，
+// 交给 handler 安全地拼接、校验并且用 http.ServeContent 流式返回；同一个位置如果已经注册了
+// :param 或者 * 路由会直接 panic，因为没法区分这个位置到底应该走哪一种匹配。
+func (s *HTTPServer) Static(urlPrefix string, fsRoot string, opts ...StaticOption) {
+	ss := &staticServer{fsys: os.DirFS(fsRoot)}
+	for _, opt := range opts {
+		opt(ss)
+	}
+	s.addStaticRoute(http.MethodGet, urlPrefix, staticFilePathParam, ss.serveHandler())
+}
+
+// serveHandler 返回真正处理请求的 HandleFunc
+func (ss *staticServer) serveHandler() HandleFunc {
+	return func(ctx *Context) {
+		rel := ctx.pathParams.ByName(staticFilePathParam)
+		rel = strings.TrimPrefix(path.Clean("/"+rel), "/")
+		if rel == "" {
+			rel = "."
+		}
+		// fs.FS 约定的合法路径不能包含 ..，也不能有多余的斜杠，用 fs.ValidPath 挡住目录穿越
+		if !fs.ValidPath(rel) {
+			ss.reply404(ctx)
+			return
+		}
+
+		f, err := ss.fsys.Open(rel)
+		if err != nil {
+			ss.reply404(ctx)
+			return
+		}
+		defer f.Close()
+		stat, err := f.Stat()
+		if err != nil {
+			ss.reply404(ctx)
+			return
+		}
+		if stat.IsDir() {
+			if !ss.listDir {
+				ss.reply404(ctx)
+				return
+			}
+			ss.serveDir(ctx, rel)
+			return
+		}
+		ss.serveFile(ctx, rel, stat, f)
+	}
+}
+
+// serveFile 设置好 ETag/Content-Type 之后用 http.ServeContent 流式返回文件内容，
+// ServeContent 会据此处理 If-Modified-Since/If-None-Match 之类的条件请求。
+// 如果客户端的 Accept-Encoding 里带了 br/gzip，并且存在对应的预压缩同名文件，优先返回它。
+func (ss *staticServer) serveFile(ctx *Context, rel string, stat fs.FileInfo, f fs.File) {
+	ctx.Resp.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, stat.ModTime().Unix(), stat.Size()))
+	if ct := mime.TypeByExtension(path.Ext(rel)); ct != "" {
+		ctx.Resp.Header().Set("Content-Type", ct)
+	}
+
+	if enc, cf, ok := ss.openPrecompressed(rel, ctx.Req.Header.Get("Accept-Encoding")); ok {
+		defer cf.Close()
+		if rs, ok := cf.(readSeekFile); ok {
+			ctx.Resp.Header().Set("Content-Encoding", enc)
+			ctx.Resp.Header().Add("Vary", "Accept-Encoding")
+			http.ServeContent(ctx.Resp, ctx.Req, rel, stat.ModTime(), rs)
+			return
+		}
+		cf.Close()
+	}
+
+	rs, ok := f.(readSeekFile)
+	if !ok {
+		ctx.Resp.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	http.ServeContent(ctx.Resp, ctx.Req, rel, stat.ModTime(), rs)
+}
+
+// readSeekFile 是 fs.File 里能被 http.ServeContent 使用的子集，os.DirFS 和 embed.FS
+// 打开出来的文件都满足这个接口
+type readSeekFile interface {
+	fs.File
+	io.Seeker
+}
+
+// openPrecompressed 按 br 优先、gzip 其次的顺序，在 acceptEncoding 允许的前提下尝试打开
+// rel 的预压缩同名文件（rel+".br"/rel+".gz"），都没有就返回 ok=false
+func (ss *staticServer) openPrecompressed(rel string, acceptEncoding string) (encoding string, f fs.File, ok bool) {
+	candidates := []struct {
+		suffix   string
+		encoding string
+	}{
+		{".br", "br"},
+		{".gz", "gzip"},
+	}
+	for _, c := range candidates {
+		if !strings.Contains(acceptEncoding, c.encoding) {
+			continue
+		}
+		if cf, err := ss.fsys.Open(rel + c.suffix); err == nil {
+			return c.encoding, cf, true
+		}
+	}
+	return "", nil, false
+}
+
+// serveDir 输出 rel 目录下的文件列表，只在 WithDirListing 打开时会被调用
+func (ss *staticServer) serveDir(ctx *Context, rel string) {
+	entries, err := fs.ReadDir(ss.fsys, rel)
+	if err != nil {
+		ctx.Resp.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	ctx.Resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+	var b strings.Builder
+	b.WriteString("<pre>\n")
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() {
+			name += "/"
+		}
+		fmt.Fprintf(&b, "<a href=\"%s\">%s</a>\n", name, name)
+	}
+	b.WriteString("</pre>\n")
+	ctx.Resp.Write([]byte(b.String()))
+}
+
+func (ss *staticServer) reply404(ctx *Context) {
+	if ss.notFound != nil {
+		ss.notFound(ctx)
+		return
+	}
+	ctx.Resp.WriteHeader(http.StatusNotFound)
+}
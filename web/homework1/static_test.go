@@ -0,0 +1,90 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newStaticServer(t *testing.T) (*HTTPServer, string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("world"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewHTTPServer()
+	s.Static("/static", dir)
+	return s, dir
+}
+
+func TestStatic_ServeFile(t *testing.T) {
+	s, _ := newStaticServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/static/a.txt", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "hello" {
+		t.Fatalf("code=%d body=%q", w.Code, w.Body.String())
+	}
+
+	req2 := httptest.NewRequest(http.MethodGet, "/static/sub/b.txt", nil)
+	w2 := httptest.NewRecorder()
+	s.ServeHTTP(w2, req2)
+	if w2.Code != http.StatusOK || w2.Body.String() != "world" {
+		t.Fatalf("code=%d body=%q", w2.Code, w2.Body.String())
+	}
+}
+
+func TestStatic_DirWithoutListingIs404(t *testing.T) {
+	s, _ := newStaticServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/static/sub", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("code = %d, want 404", w.Code)
+	}
+}
+
+func TestStatic_RejectsTraversal(t *testing.T) {
+	s, _ := newStaticServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/static/../../../etc/passwd", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("code = %d, want 404", w.Code)
+	}
+}
+
+func TestStatic_ConflictsWithParamRoute(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic registering :param over an existing static mount")
+		}
+	}()
+	s := NewHTTPServer()
+	s.Static("/assets", t.TempDir())
+	s.GET("/assets/:name", mockHandler)
+}
+
+func TestStatic_NotFoundHook(t *testing.T) {
+	s := NewHTTPServer()
+	dir := t.TempDir()
+	called := false
+	s.Static("/static", dir, WithStaticNotFound(func(ctx *Context) {
+		called = true
+		ctx.Resp.WriteHeader(http.StatusTeapot)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/static/missing.txt", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if !called || w.Code != http.StatusTeapot {
+		t.Fatalf("called=%v code=%d", called, w.Code)
+	}
+}